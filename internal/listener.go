@@ -0,0 +1,26 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// HandlerListener is implemented by types that want to observe the
+// lifecycle of a wrapped Lambda handler invocation. Listeners are notified
+// in registration order on HandlerStarted, and in reverse registration
+// order on HandlerFinished/HandlerPanicked, so a listener that wraps
+// another (e.g. tracing around logging) unwinds the same way deferred
+// calls would.
+type HandlerListener interface {
+	// HandlerStarted is called with the raw event payload before the
+	// user's handler is invoked.
+	HandlerStarted(ctx context.Context, msg json.RawMessage)
+	// HandlerFinished is called after the user's handler returns without
+	// panicking, whether or not it returned an error. It is also called when
+	// the event could not be decoded and the handler was never invoked.
+	HandlerFinished(ctx context.Context)
+	// HandlerPanicked is called instead of HandlerFinished when the
+	// user's handler panics. recovered is the value passed to panic(),
+	// and stack holds the captured stack trace.
+	HandlerPanicked(ctx context.Context, recovered interface{}, stack []byte)
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// coldStart is true for the first invocation handled by this process and
+// false for every invocation after that.
+var coldStart = true
+
+type logRecord struct {
+	Message     string  `json:"message"`
+	RequestID   string  `json:"request_id,omitempty"`
+	ColdStart   bool    `json:"cold_start"`
+	RemainingMS int64   `json:"remaining_time_ms,omitempty"`
+	DurationMS  float64 `json:"duration_ms,omitempty"`
+}
+
+// LoggingListener emits a structured JSON log line at the start and end of
+// every invocation, so invocation boundaries can be grepped out of a
+// function's logs and correlated with other telemetry.
+type LoggingListener struct {
+	startTime time.Time
+}
+
+// MakeLoggingListener creates a new LoggingListener.
+func MakeLoggingListener() *LoggingListener {
+	return &LoggingListener{}
+}
+
+func (ll *LoggingListener) HandlerStarted(ctx context.Context, msg json.RawMessage) {
+	ll.startTime = time.Now()
+
+	record := logRecord{
+		Message:   "handler_started",
+		ColdStart: coldStart,
+	}
+	populateInvocationFields(ctx, &record)
+	coldStart = false
+
+	emitLogRecord(record)
+}
+
+func (ll *LoggingListener) HandlerFinished(ctx context.Context) {
+	record := logRecord{
+		Message:    "handler_finished",
+		DurationMS: float64(time.Since(ll.startTime)) / float64(time.Millisecond),
+	}
+	populateInvocationFields(ctx, &record)
+
+	emitLogRecord(record)
+}
+
+func (ll *LoggingListener) HandlerPanicked(ctx context.Context, recovered interface{}, stack []byte) {
+	ll.HandlerFinished(ctx)
+}
+
+func populateInvocationFields(ctx context.Context, record *logRecord) {
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		record.RequestID = lc.AwsRequestID
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		record.RemainingMS = int64(time.Until(deadline) / time.Millisecond)
+	}
+}
+
+func emitLogRecord(record logRecord) {
+	bytes, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("datadog: failed to marshal log record: %v", err)
+		return
+	}
+	log.Println(string(bytes))
+}
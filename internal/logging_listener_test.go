@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+)
+
+// captureLogOutput redirects the standard logger for the duration of fn and
+// returns each line it printed, with the timestamp prefix disabled so
+// assertions can compare against raw JSON.
+func captureLogOutput(t *testing.T, fn func()) []string {
+	t.Helper()
+
+	var buf strings.Builder
+	originalOutput := log.Writer()
+	originalFlags := log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(originalOutput)
+		log.SetFlags(originalFlags)
+	}()
+
+	fn()
+
+	trimmed := strings.TrimRight(buf.String(), "\n")
+	if trimmed == "" {
+		return nil
+	}
+	return strings.Split(trimmed, "\n")
+}
+
+func TestLoggingListenerHandlerStartedEmitsColdStartRecord(t *testing.T) {
+	coldStart = true
+	ll := MakeLoggingListener()
+
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "test-request-id"})
+	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(5*time.Second))
+	defer cancel()
+
+	lines := captureLogOutput(t, func() {
+		ll.HandlerStarted(ctx, json.RawMessage(`{}`))
+	})
+	assert.Len(t, lines, 1)
+
+	var record logRecord
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "handler_started", record.Message)
+	assert.Equal(t, "test-request-id", record.RequestID)
+	assert.True(t, record.ColdStart)
+	assert.Greater(t, record.RemainingMS, int64(0))
+}
+
+func TestLoggingListenerColdStartOnlyTrueOnce(t *testing.T) {
+	coldStart = true
+	ll := MakeLoggingListener()
+	ctx := context.Background()
+
+	first := captureLogOutput(t, func() { ll.HandlerStarted(ctx, json.RawMessage(`{}`)) })
+	second := captureLogOutput(t, func() { ll.HandlerStarted(ctx, json.RawMessage(`{}`)) })
+
+	var firstRecord, secondRecord logRecord
+	assert.NoError(t, json.Unmarshal([]byte(first[0]), &firstRecord))
+	assert.NoError(t, json.Unmarshal([]byte(second[0]), &secondRecord))
+
+	assert.True(t, firstRecord.ColdStart)
+	assert.False(t, secondRecord.ColdStart)
+}
+
+func TestLoggingListenerHandlerFinishedEmitsDuration(t *testing.T) {
+	ll := MakeLoggingListener()
+	ctx := context.Background()
+
+	captureLogOutput(t, func() { ll.HandlerStarted(ctx, json.RawMessage(`{}`)) })
+	time.Sleep(time.Millisecond)
+
+	lines := captureLogOutput(t, func() { ll.HandlerFinished(ctx) })
+	assert.Len(t, lines, 1)
+
+	var record logRecord
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "handler_finished", record.Message)
+	assert.Greater(t, record.DurationMS, float64(0))
+}
+
+func TestLoggingListenerHandlerPanickedEmitsFinishedRecord(t *testing.T) {
+	ll := MakeLoggingListener()
+	ctx := context.Background()
+
+	captureLogOutput(t, func() { ll.HandlerStarted(ctx, json.RawMessage(`{}`)) })
+
+	lines := captureLogOutput(t, func() {
+		ll.HandlerPanicked(ctx, "boom", []byte("stack"))
+	})
+	assert.Len(t, lines, 1)
+
+	var record logRecord
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "handler_finished", record.Message)
+}
+
+// TestWrapHandlerIntegratesBuiltinListeners exercises LoggingListener and
+// PanicListener together through WrapHandler, the same way a customer would
+// wire them up, to confirm they don't interfere with each other or with
+// panic recovery.
+func TestWrapHandlerIntegratesBuiltinListeners(t *testing.T) {
+	coldStart = true
+	mc := &mockMetricsClient{}
+	logging := MakeLoggingListener()
+	panicListener := MakePanicListener(mc)
+
+	handler := func(ctx context.Context, request mockNonProxyEvent) (int, error) {
+		panic("integration boom")
+	}
+
+	payload := loadRawJSON(t, "testdata/non-proxy-no-metadata.json")
+	wrappedHandler := WrapHandler(handler, logging, panicListener).(func(context.Context, json.RawMessage) (interface{}, error))
+
+	var err error
+	lines := captureLogOutput(t, func() {
+		_, err = wrappedHandler(context.Background(), *payload)
+	})
+
+	assert.Error(t, err)
+	assert.Len(t, lines, 2)
+
+	var started, finished logRecord
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &started))
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &finished))
+	assert.Equal(t, "handler_started", started.Message)
+	assert.Equal(t, "handler_finished", finished.Message)
+
+	assert.Len(t, mc.calls, 1)
+	assert.Equal(t, "aws.lambda.enhanced.errors", mc.calls[0].name)
+}
@@ -0,0 +1,48 @@
+// Package metrics batches the custom distribution metrics a Lambda
+// function emits during an invocation and flushes them to Datadog.
+package metrics
+
+import "time"
+
+// MetricType identifies the kind of metric an APIMetric carries.
+type MetricType string
+
+// DistributionType is the only MetricType this library currently submits.
+const DistributionType MetricType = "distribution"
+
+// APIMetric is the over-the-wire shape of a batch of metric points, in the
+// form the Datadog API expects.
+type APIMetric struct {
+	Name       string      `json:"metric"`
+	Tags       []string    `json:"tags,omitempty"`
+	MetricType MetricType  `json:"type"`
+	Points     [][]float64 `json:"points"`
+}
+
+// Client submits a batch of metrics to Datadog.
+type Client interface {
+	SendMetrics(metrics []APIMetric) error
+}
+
+// TimeService abstracts the parts of the time package Processor depends
+// on, so tests can drive flush timing and retry backoff deterministically.
+type TimeService interface {
+	NewTicker(duration time.Duration) *time.Ticker
+	Now() time.Time
+	Sleep(duration time.Duration)
+}
+
+// realTimeService is the TimeService used outside of tests.
+type realTimeService struct{}
+
+func (realTimeService) NewTicker(duration time.Duration) *time.Ticker {
+	return time.NewTicker(duration)
+}
+
+func (realTimeService) Now() time.Time {
+	return time.Now()
+}
+
+func (realTimeService) Sleep(duration time.Duration) {
+	time.Sleep(duration)
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import "strings"
+
+// Distribution is a single distribution metric collected during an
+// invocation: a name, a set of tags, and the raw values observed. Values
+// are batched by Processor and flushed together with any other
+// Distribution sharing the same name and tags.
+type Distribution struct {
+	Name   string
+	Tags   []string
+	Values []float64
+}
+
+// key identifies the batch a Distribution's points should be merged into:
+// distributions with the same name and tags share one APIMetric, with all
+// of their points concatenated.
+func (d *Distribution) key() string {
+	return d.Name + "|" + strings.Join(d.Tags, ",")
+}
+
+func (d *Distribution) toAPIMetric(now float64) APIMetric {
+	points := make([][]float64, len(d.Values))
+	for i, value := range d.Values {
+		points[i] = []float64{now, value}
+	}
+	return APIMetric{
+		Name:       d.Name,
+		Tags:       d.Tags,
+		MetricType: DistributionType,
+		Points:     points,
+	}
+}
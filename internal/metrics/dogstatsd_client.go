@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	dogStatsDUDPAddr    = "127.0.0.1:8125"
+	dogStatsDSocketPath = "/var/run/datadog/dsd.socket"
+	extensionEnvVar     = "DD_LAMBDA_EXTENSION"
+	// maxDatagramBytes keeps a single write under the common 8KB UDP MTU,
+	// so the kernel doesn't have to fragment it.
+	maxDatagramBytes = 8 * 1024
+)
+
+// DogStatsDClient submits metrics using the DogStatsD line protocol over a
+// Unix domain socket or UDP, for use when the Datadog Lambda Extension is
+// running alongside the function. Submitting through the extension avoids
+// an HTTP round trip (and an API key) on every invocation.
+type DogStatsDClient struct {
+	conn net.Conn
+}
+
+// ExtensionDetected reports whether the Datadog Lambda Extension appears to
+// be running alongside this function: either its DogStatsD Unix domain
+// socket exists, or DD_LAMBDA_EXTENSION is set.
+func ExtensionDetected() bool {
+	if os.Getenv(extensionEnvVar) != "" {
+		return true
+	}
+	_, err := os.Stat(dogStatsDSocketPath)
+	return err == nil
+}
+
+// SelectClient returns a Client suited to the current environment: a
+// DogStatsDClient talking to the Datadog Lambda Extension when it's
+// detected, so metrics skip the per-invocation HTTP flush and don't need
+// an API key; otherwise apiClient is returned unchanged.
+func SelectClient(apiClient Client) Client {
+	if !ExtensionDetected() {
+		return apiClient
+	}
+
+	client, err := MakeDogStatsDClient()
+	if err != nil {
+		return apiClient
+	}
+	return client
+}
+
+// MakeDogStatsDClient connects to the Datadog Lambda Extension's DogStatsD
+// listener, preferring its Unix domain socket and falling back to UDP on
+// 127.0.0.1:8125.
+func MakeDogStatsDClient() (*DogStatsDClient, error) {
+	if _, err := os.Stat(dogStatsDSocketPath); err == nil {
+		if conn, dialErr := net.Dial("unixgram", dogStatsDSocketPath); dialErr == nil {
+			return newDogStatsDClient(conn), nil
+		}
+	}
+
+	conn, err := net.Dial("udp", dogStatsDUDPAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to DogStatsD: %v", err)
+	}
+	return newDogStatsDClient(conn), nil
+}
+
+func newDogStatsDClient(conn net.Conn) *DogStatsDClient {
+	return &DogStatsDClient{conn: conn}
+}
+
+// SendMetrics writes metrics to the DogStatsD socket, one line per
+// distribution value, batched into writes that stay under maxDatagramBytes.
+func (c *DogStatsDClient) SendMetrics(metrics []APIMetric) error {
+	var batch strings.Builder
+
+	flush := func() error {
+		if batch.Len() == 0 {
+			return nil
+		}
+		_, err := c.conn.Write([]byte(batch.String()))
+		batch.Reset()
+		return err
+	}
+
+	for _, metric := range metrics {
+		tags := strings.Join(metric.Tags, ",")
+		for _, point := range metric.Points {
+			line := formatDogStatsDLine(metric.Name, point[1], tags)
+			if batch.Len() > 0 && batch.Len()+len(line) > maxDatagramBytes {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			batch.WriteString(line)
+		}
+	}
+
+	return flush()
+}
+
+// Close closes the underlying connection.
+func (c *DogStatsDClient) Close() error {
+	return c.conn.Close()
+}
+
+func formatDogStatsDLine(name string, value float64, tags string) string {
+	line := name + ":" + strconv.FormatFloat(value, 'g', -1, 64) + "|d"
+	if tags != "" {
+		line += "|#" + tags
+	}
+	return line + "\n"
+}
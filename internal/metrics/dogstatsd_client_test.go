@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockConn struct {
+	writes [][]byte
+}
+
+func (c *mockConn) Read(b []byte) (int, error)         { return 0, io.EOF }
+func (c *mockConn) Close() error                       { return nil }
+func (c *mockConn) LocalAddr() net.Addr                { return nil }
+func (c *mockConn) RemoteAddr() net.Addr               { return nil }
+func (c *mockConn) SetDeadline(t time.Time) error      { return nil }
+func (c *mockConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *mockConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func (c *mockConn) Write(b []byte) (int, error) {
+	cp := make([]byte, len(b))
+	copy(cp, b)
+	c.writes = append(c.writes, cp)
+	return len(b), nil
+}
+
+func TestDogStatsDClientWritesLineProtocol(t *testing.T) {
+	conn := &mockConn{}
+	client := newDogStatsDClient(conn)
+
+	err := client.SendMetrics([]APIMetric{
+		{
+			Name:       "metric-1",
+			Tags:       []string{"tag1", "tag2"},
+			MetricType: DistributionType,
+			Points: [][]float64{
+				{0, 1},
+				{0, 2},
+			},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{
+		[]byte("metric-1:1|d|#tag1,tag2\nmetric-1:2|d|#tag1,tag2\n"),
+	}, conn.writes)
+}
+
+func TestDogStatsDClientOmitsTagsPipeWhenUntagged(t *testing.T) {
+	conn := &mockConn{}
+	client := newDogStatsDClient(conn)
+
+	err := client.SendMetrics([]APIMetric{
+		{Name: "metric-1", MetricType: DistributionType, Points: [][]float64{{0, 5}}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("metric-1:5|d\n")}, conn.writes)
+}
+
+func TestDogStatsDClientBatchesUnderMTU(t *testing.T) {
+	conn := &mockConn{}
+	client := newDogStatsDClient(conn)
+
+	points := make([][]float64, 3000)
+	for i := range points {
+		points[i] = []float64{0, float64(i)}
+	}
+
+	err := client.SendMetrics([]APIMetric{
+		{Name: "metric-1", MetricType: DistributionType, Points: points},
+	})
+
+	assert.NoError(t, err)
+	assert.Greater(t, len(conn.writes), 1)
+
+	var combined []byte
+	for _, write := range conn.writes {
+		assert.LessOrEqual(t, len(write), maxDatagramBytes)
+		combined = append(combined, write...)
+	}
+
+	expected := ""
+	for i := range points {
+		expected += formatDogStatsDLine("metric-1", float64(i), "")
+	}
+	assert.Equal(t, expected, string(combined))
+}
+
+func TestExtensionDetectedViaEnvVar(t *testing.T) {
+	os.Setenv(extensionEnvVar, "true")
+	defer os.Unsetenv(extensionEnvVar)
+
+	assert.True(t, ExtensionDetected())
+}
+
+func TestExtensionNotDetectedByDefault(t *testing.T) {
+	os.Unsetenv(extensionEnvVar)
+
+	assert.False(t, ExtensionDetected())
+}
+
+type fakeAPIClient struct{}
+
+func (fakeAPIClient) SendMetrics(metrics []APIMetric) error { return nil }
+
+func TestSelectClientFallsBackToAPIClientWithoutExtension(t *testing.T) {
+	os.Unsetenv(extensionEnvVar)
+	api := fakeAPIClient{}
+
+	client := SelectClient(api)
+
+	assert.Equal(t, api, client)
+}
@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Processor batches Distribution metrics added during a Lambda invocation
+// and flushes them to a Client on a fixed interval and at the end of the
+// invocation.
+type Processor struct {
+	mutex         sync.Mutex
+	batch         map[string]*Distribution
+	client        Client
+	timeService   TimeService
+	batchInterval time.Duration
+	retryPolicy   RetryPolicy
+	deadline      time.Time
+
+	ticker   *time.Ticker
+	stopChan chan struct{}
+	doneChan chan struct{}
+}
+
+// MakeProcessor creates a Processor that flushes to client every
+// batchInterval and at FinishProcessing. shouldRetry maps to
+// DefaultRetryPolicy when true and NoRetryPolicy when false; use
+// MakeProcessorWithRetryPolicy for finer control over retry behavior.
+func MakeProcessor(client Client, timeService TimeService, batchInterval time.Duration, shouldRetry bool) *Processor {
+	return MakeProcessorWithRetryPolicy(client, timeService, batchInterval, retryPolicyFromShouldRetry(shouldRetry))
+}
+
+// MakeProcessorWithRetryPolicy creates a Processor that flushes to client
+// every batchInterval and at FinishProcessing, retrying a failed flush
+// according to retryPolicy.
+func MakeProcessorWithRetryPolicy(client Client, timeService TimeService, batchInterval time.Duration, retryPolicy RetryPolicy) *Processor {
+	return &Processor{
+		batch:         map[string]*Distribution{},
+		client:        client,
+		timeService:   timeService,
+		batchInterval: batchInterval,
+		retryPolicy:   retryPolicy,
+	}
+}
+
+// AddMetric adds a Distribution to the current batch, merging its values
+// into any previously added Distribution with the same name and tags.
+func (p *Processor) AddMetric(distribution *Distribution) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	key := distribution.key()
+	existing, ok := p.batch[key]
+	if !ok {
+		p.batch[key] = &Distribution{
+			Name:   distribution.Name,
+			Tags:   distribution.Tags,
+			Values: append([]float64{}, distribution.Values...),
+		}
+		return
+	}
+	existing.Values = append(existing.Values, distribution.Values...)
+}
+
+// StartProcessing begins flushing the batch to the Client every
+// batchInterval, until FinishProcessing is called. When ctx carries a
+// deadline (as the context the Lambda Go runtime passes to a handler
+// does), that deadline clamps retryPolicy.Budget for every flush made
+// during this invocation, so a flush can never retry past however much
+// execution time actually remains - see (RetryPolicy).Budget.
+func (p *Processor) StartProcessing(ctx context.Context) {
+	p.setDeadline(ctx)
+
+	p.ticker = p.timeService.NewTicker(p.batchInterval)
+	p.stopChan = make(chan struct{})
+	p.doneChan = make(chan struct{})
+
+	go func() {
+		defer close(p.doneChan)
+		for {
+			select {
+			case <-p.ticker.C:
+				p.flush()
+			case <-p.stopChan:
+				p.flush()
+				return
+			}
+		}
+	}()
+}
+
+// FinishProcessing stops the periodic flush started by StartProcessing and
+// flushes whatever remains in the current batch, blocking until that final
+// flush (including any retries) completes. If StartProcessing was never
+// called, ctx's deadline clamps the final flush's retry budget the same
+// way it would have.
+func (p *Processor) FinishProcessing(ctx context.Context) {
+	p.setDeadline(ctx)
+
+	if p.stopChan == nil {
+		p.flush()
+		return
+	}
+
+	p.ticker.Stop()
+	close(p.stopChan)
+	<-p.doneChan
+}
+
+// setDeadline records ctx's deadline, if it has one, so sendWithRetry can
+// clamp retryPolicy.Budget by however much invocation time actually
+// remains. A Processor is typically constructed once and reused across
+// warm invocations, so this is refreshed at the start of every
+// StartProcessing/FinishProcessing pair rather than baked in at
+// construction time.
+func (p *Processor) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if ok {
+		p.deadline = deadline
+	} else {
+		p.deadline = time.Time{}
+	}
+}
+
+func (p *Processor) flush() {
+	p.mutex.Lock()
+	batch := p.batch
+	p.batch = map[string]*Distribution{}
+	p.mutex.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	now := float64(p.timeService.Now().Unix())
+	metrics := make([]APIMetric, 0, len(batch))
+	for _, distribution := range batch {
+		metrics = append(metrics, distribution.toAPIMetric(now))
+	}
+
+	p.sendWithRetry(metrics)
+}
+
+func (p *Processor) sendWithRetry(batch []APIMetric) {
+	start := p.timeService.Now()
+	maxAttempts := p.retryPolicy.maxAttempts()
+	budget := p.retryBudget(start)
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		err := p.client.SendMetrics(batch)
+		if err == nil || !isRetryable(err) {
+			return
+		}
+		if attempt == maxAttempts-1 {
+			return
+		}
+
+		if budget < 0 {
+			// The invocation's deadline has already passed: no time
+			// remains for a retry, as opposed to budget == 0, which
+			// means retryPolicy.Budget itself imposes no cap.
+			return
+		}
+
+		backoff := p.retryPolicy.backoff(attempt)
+		if budget > 0 && p.timeService.Now().Sub(start)+backoff >= budget {
+			return
+		}
+		p.timeService.Sleep(backoff)
+	}
+}
+
+// retryBudget returns how long sendWithRetry may spend retrying a flush
+// that started at start: retryPolicy.Budget, clamped to however much time
+// remains before the current invocation's deadline, if one is known. This
+// is what keeps a reused, warm Processor's static Budget from outliving
+// the invocation it's actually flushing for.
+//
+// A return value of 0 means retryPolicy.Budget itself imposes no cap (no
+// deadline is known, and the policy's static Budget is <= 0). A negative
+// return value means the opposite: the invocation's deadline has already
+// passed, so no time at all remains for a retry. sendWithRetry must treat
+// these two cases differently, rather than overloading zero for both.
+func (p *Processor) retryBudget(start time.Time) time.Duration {
+	p.mutex.Lock()
+	deadline := p.deadline
+	p.mutex.Unlock()
+
+	budget := p.retryPolicy.Budget
+	if deadline.IsZero() {
+		if budget < 0 {
+			return 0
+		}
+		return budget
+	}
+
+	remaining := deadline.Sub(start)
+	if remaining <= 0 {
+		return -1
+	}
+	if budget <= 0 || remaining < budget {
+		return remaining
+	}
+	return budget
+}
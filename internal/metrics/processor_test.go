@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -18,6 +19,7 @@ type (
 	mockTimeService struct {
 		now        time.Time
 		tickerChan chan time.Time
+		sleeps     []time.Duration
 	}
 )
 
@@ -51,6 +53,11 @@ func (ts *mockTimeService) Now() time.Time {
 	return ts.now
 }
 
+func (ts *mockTimeService) Sleep(d time.Duration) {
+	ts.sleeps = append(ts.sleeps, d)
+	ts.now = ts.now.Add(d)
+}
+
 func TestProcessorBatches(t *testing.T) {
 	mc := makeMockClient()
 	mts := makeMockTimeService()
@@ -74,8 +81,8 @@ func TestProcessorBatches(t *testing.T) {
 	processor.AddMetric(&d1)
 	processor.AddMetric(&d2)
 
-	processor.StartProcessing()
-	processor.FinishProcessing()
+	processor.StartProcessing(context.Background())
+	processor.FinishProcessing(context.Background())
 
 	firstBatch := <-mc.batches
 
@@ -127,7 +134,7 @@ func TestProcessorBatchesPerTick(t *testing.T) {
 		Values: []float64{6},
 	}
 
-	processor.StartProcessing()
+	processor.StartProcessing(context.Background())
 
 	processor.AddMetric(&d1)
 	processor.AddMetric(&d2)
@@ -142,7 +149,7 @@ func TestProcessorBatchesPerTick(t *testing.T) {
 	processor.AddMetric(&d3)
 	processor.AddMetric(&d4)
 
-	processor.FinishProcessing()
+	processor.FinishProcessing(context.Background())
 	secondBatch := <-mc.batches
 	batches := [][]APIMetric{firstBatch, secondBatch}
 
@@ -172,14 +179,25 @@ func TestProcessorBatchesPerTick(t *testing.T) {
 	}, batches)
 }
 
-func TestProcessorPerformsRetry(t *testing.T) {
+type statusError struct {
+	status int
+}
+
+func (e statusError) Error() string {
+	return "request failed"
+}
+
+func (e statusError) StatusCode() int {
+	return e.status
+}
+
+func TestProcessorLegacyShouldRetryShim(t *testing.T) {
 	mc := makeMockClient()
 	mts := makeMockTimeService()
 
 	mts.now, _ = time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
 
-	shouldRetry := true
-	processor := MakeProcessor(&mc, &mts, 1000, shouldRetry)
+	processor := MakeProcessor(&mc, &mts, 1000, true)
 
 	d1 := Distribution{
 		Name:   "metric-1",
@@ -191,7 +209,181 @@ func TestProcessorPerformsRetry(t *testing.T) {
 
 	processor.AddMetric(&d1)
 
-	processor.FinishProcessing()
+	processor.FinishProcessing(context.Background())
+
+	assert.Equal(t, DefaultRetryPolicy.MaxAttempts, mc.sendMetricsCalledCount)
+}
+
+func TestProcessorRetryBackoffSequence(t *testing.T) {
+	mc := makeMockClient()
+	mts := makeMockTimeService()
+
+	mts.now, _ = time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+
+	policy := RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         false,
+		Budget:         10 * time.Second,
+	}
+	processor := MakeProcessorWithRetryPolicy(&mc, &mts, 1000, policy)
+
+	mc.err = errors.New("server error")
+
+	processor.AddMetric(&Distribution{Name: "metric-1", Values: []float64{1}})
+	processor.FinishProcessing(context.Background())
+
+	assert.Equal(t, 4, mc.sendMetricsCalledCount)
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+	}, mts.sleeps)
+}
+
+func TestProcessorRetryBudgetCutoff(t *testing.T) {
+	mc := makeMockClient()
+	mts := makeMockTimeService()
+
+	mts.now, _ = time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+
+	policy := RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         false,
+		Budget:         500 * time.Millisecond,
+	}
+	processor := MakeProcessorWithRetryPolicy(&mc, &mts, 1000, policy)
+
+	mc.err = errors.New("server error")
+
+	processor.AddMetric(&Distribution{Name: "metric-1", Values: []float64{1}})
+	processor.FinishProcessing(context.Background())
+
+	// Backoffs would be 100ms, 200ms, 400ms, 800ms, ... A 500ms budget
+	// allows the first two (cumulative 300ms) but not the third, since
+	// 300ms+400ms would blow past it.
+	assert.Equal(t, 3, mc.sendMetricsCalledCount)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, mts.sleeps)
+}
+
+func TestProcessorRetryClampedByInvocationDeadline(t *testing.T) {
+	mc := makeMockClient()
+	mts := makeMockTimeService()
+
+	mts.now, _ = time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+
+	policy := RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         false,
+		Budget:         5 * time.Second,
+	}
+	processor := MakeProcessorWithRetryPolicy(&mc, &mts, 1000, policy)
+
+	mc.err = errors.New("server error")
+
+	// The invocation's own deadline (500ms away) is tighter than the
+	// policy's static 5s Budget, so it - not Budget - is what should
+	// cap retrying.
+	ctx, cancel := context.WithDeadline(context.Background(), mts.now.Add(500*time.Millisecond))
+	defer cancel()
+
+	processor.AddMetric(&Distribution{Name: "metric-1", Values: []float64{1}})
+	processor.FinishProcessing(ctx)
 
 	assert.Equal(t, 3, mc.sendMetricsCalledCount)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, mts.sleeps)
+}
+
+func TestProcessorNoRetryWhenDeadlineAlreadyPassed(t *testing.T) {
+	mc := makeMockClient()
+	mts := makeMockTimeService()
+
+	mts.now, _ = time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+
+	policy := RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         false,
+		Budget:         5 * time.Second,
+	}
+	processor := MakeProcessorWithRetryPolicy(&mc, &mts, 1000, policy)
+
+	mc.err = errors.New("server error")
+
+	// The invocation's deadline is already behind start: no time at all
+	// remains, so the first attempt's failure should end the flush
+	// outright rather than falling through to a full retry sequence.
+	ctx, cancel := context.WithDeadline(context.Background(), mts.now.Add(-time.Second))
+	defer cancel()
+
+	processor.AddMetric(&Distribution{Name: "metric-1", Values: []float64{1}})
+	processor.FinishProcessing(ctx)
+
+	assert.Equal(t, 1, mc.sendMetricsCalledCount)
+	assert.Empty(t, mts.sleeps)
+}
+
+func TestProcessorRetryUsesStaticBudgetWithoutDeadline(t *testing.T) {
+	mc := makeMockClient()
+	mts := makeMockTimeService()
+
+	mts.now, _ = time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+
+	policy := RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         false,
+		Budget:         500 * time.Millisecond,
+	}
+	processor := MakeProcessorWithRetryPolicy(&mc, &mts, 1000, policy)
+
+	mc.err = errors.New("server error")
+
+	processor.AddMetric(&Distribution{Name: "metric-1", Values: []float64{1}})
+	processor.FinishProcessing(context.Background())
+
+	assert.Equal(t, 3, mc.sendMetricsCalledCount)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, mts.sleeps)
+}
+
+func TestProcessorDoesNotRetryPermanentClientError(t *testing.T) {
+	mc := makeMockClient()
+	mts := makeMockTimeService()
+
+	mts.now, _ = time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+
+	processor := MakeProcessorWithRetryPolicy(&mc, &mts, 1000, DefaultRetryPolicy)
+	mc.err = statusError{status: 400}
+
+	processor.AddMetric(&Distribution{Name: "metric-1", Values: []float64{1}})
+	processor.FinishProcessing(context.Background())
+
+	assert.Equal(t, 1, mc.sendMetricsCalledCount)
+}
+
+func TestProcessorRetriesThrottledClientError(t *testing.T) {
+	mc := makeMockClient()
+	mts := makeMockTimeService()
+
+	mts.now, _ = time.Parse(time.RFC3339, "2006-01-02T15:04:05Z")
+
+	processor := MakeProcessorWithRetryPolicy(&mc, &mts, 1000, DefaultRetryPolicy)
+	mc.err = statusError{status: 429}
+
+	processor.AddMetric(&Distribution{Name: "metric-1", Values: []float64{1}})
+	processor.FinishProcessing(context.Background())
+
+	assert.Equal(t, DefaultRetryPolicy.MaxAttempts, mc.sendMetricsCalledCount)
 }
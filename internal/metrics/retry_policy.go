@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// HTTPStatusError is implemented by a Client error that carries an HTTP
+// status code, letting RetryPolicy tell a permanent 4xx failure (bad API
+// key, malformed payload) apart from a transient one worth retrying. A
+// Client that can't distinguish status codes can simply not implement it;
+// its errors are then always treated as retryable.
+type HTTPStatusError interface {
+	error
+	StatusCode() int
+}
+
+// RetryPolicy controls how Processor retries a batch that failed to send.
+// Backoff follows AWS's recommended "full jitter" algorithm: each retry
+// sleeps a random duration between zero and the capped exponential
+// backoff for that attempt, so that many concurrent Lambda invocations
+// failing at once don't all retry in lockstep.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times SendMetrics is called for
+	// a given batch, including the first attempt. A value <= 1 disables
+	// retries entirely.
+	MaxAttempts int
+	// InitialBackoff is the base delay the backoff for attempt 0 is
+	// computed from.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay computed for any single attempt.
+	MaxBackoff time.Duration
+	// Multiplier is applied to InitialBackoff once per attempt.
+	Multiplier float64
+	// Jitter enables AWS's full-jitter algorithm; when false, the capped
+	// exponential backoff is used as-is.
+	Jitter bool
+	// Budget caps the total time Processor will spend retrying a single
+	// batch. A zero Budget means no static cap. Processor additionally
+	// clamps whichever budget applies (including a zero Budget) to
+	// however much time remains before the current invocation's
+	// deadline, so a flush never outlives the Lambda invocation it
+	// belongs to - see (Processor).StartProcessing.
+	Budget time.Duration
+
+	// rand supplies jitter. It defaults to a package-level source and is
+	// only ever overridden by tests that need a deterministic sequence.
+	rand *rand.Rand
+}
+
+// DefaultRetryPolicy is what MakeProcessor's legacy shouldRetry parameter
+// maps true to.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     3 * time.Second,
+	Multiplier:     2,
+	Jitter:         true,
+	Budget:         5 * time.Second,
+}
+
+// NoRetryPolicy never retries a failed send, matching what the legacy
+// shouldRetry parameter maps false to.
+var NoRetryPolicy = RetryPolicy{MaxAttempts: 1}
+
+func retryPolicyFromShouldRetry(shouldRetry bool) RetryPolicy {
+	if shouldRetry {
+		return DefaultRetryPolicy
+	}
+	return NoRetryPolicy
+}
+
+func (rp RetryPolicy) maxAttempts() int {
+	if rp.MaxAttempts <= 0 {
+		return 1
+	}
+	return rp.MaxAttempts
+}
+
+// backoff computes the delay to sleep after the given zero-indexed attempt
+// fails.
+func (rp RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := float64(rp.InitialBackoff) * math.Pow(rp.Multiplier, float64(attempt))
+	if max := float64(rp.MaxBackoff); rp.MaxBackoff > 0 && backoff > max {
+		backoff = max
+	}
+	if !rp.Jitter {
+		return time.Duration(backoff)
+	}
+
+	var source jitterSource = globalRand
+	if rp.rand != nil {
+		source = rp.rand
+	}
+	return time.Duration(source.Float64() * backoff)
+}
+
+// jitterSource is the minimal surface backoff needs from a jitter source,
+// satisfied by both *rand.Rand (for tests that pin a deterministic
+// sequence) and globalRand's mutex-guarded wrapper.
+type jitterSource interface {
+	Float64() float64
+}
+
+// globalRand is the default jitter source for every RetryPolicy that
+// doesn't set its own. Processor flushes can run concurrently across
+// multiple warm Processors in the same process, so unlike rp.rand (always
+// scoped to a single Processor/test), this source must be safe for
+// concurrent use - a plain *rand.Rand isn't.
+var globalRand jitterSource = &lockedRand{source: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+type lockedRand struct {
+	mutex  sync.Mutex
+	source *rand.Rand
+}
+
+func (r *lockedRand) Float64() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.source.Float64()
+}
+
+// isRetryable reports whether a failed SendMetrics call is worth retrying.
+// HTTP 4xx responses are permanent failures (and retrying them would just
+// repeat the same rejection) except for 408 Request Timeout and 429 Too
+// Many Requests, which are expected to succeed on a later attempt.
+func isRetryable(err error) bool {
+	statusErr, ok := err.(HTTPStatusError)
+	if !ok {
+		return true
+	}
+
+	status := statusErr.StatusCode()
+	if status == 408 || status == 429 {
+		return true
+	}
+	return status < 400 || status >= 500
+}
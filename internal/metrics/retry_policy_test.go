@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyBackoffWithoutJitterIsExact(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         false,
+	}
+
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(0))
+	assert.Equal(t, 200*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 400*time.Millisecond, policy.backoff(2))
+}
+
+func TestRetryPolicyBackoffWithJitterStaysWithinCappedBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         true,
+		rand:           rand.New(rand.NewSource(42)),
+	}
+
+	cappedBackoff := 400 * time.Millisecond // uncapped backoff for attempt 2
+	for i := 0; i < 50; i++ {
+		jittered := policy.backoff(2)
+		assert.GreaterOrEqual(t, jittered, time.Duration(0))
+		assert.Less(t, jittered, cappedBackoff)
+	}
+}
+
+func TestRetryPolicyBackoffWithJitterCapsAtMaxBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     500 * time.Millisecond,
+		Multiplier:     2,
+		Jitter:         true,
+		rand:           rand.New(rand.NewSource(7)),
+	}
+
+	// Uncapped backoff for attempt 5 would be 3.2s, so jitter must stay
+	// within MaxBackoff rather than the uncapped exponential value.
+	for i := 0; i < 50; i++ {
+		jittered := policy.backoff(5)
+		assert.GreaterOrEqual(t, jittered, time.Duration(0))
+		assert.Less(t, jittered, 500*time.Millisecond)
+	}
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// MetricsClient is the minimal surface PanicListener needs in order to
+// submit an error-count metric for a recovered panic. It's declared here,
+// rather than imported, so this package doesn't need to depend on the
+// metrics package. No type in this library implements it: metrics.Processor
+// takes a *Distribution through AddMetric instead.
+type MetricsClient interface {
+	AddDistributionMetric(name string, value float64, tags ...string)
+}
+
+// PanicListener submits a Datadog error-count metric whenever the wrapped
+// handler panics. WrapHandler itself always recovers a panic and turns it
+// into a returned error so the Lambda process doesn't crash; this listener
+// is what makes that panic visible in Datadog.
+type PanicListener struct {
+	metricsClient MetricsClient
+}
+
+// MakePanicListener creates a new PanicListener that reports to
+// metricsClient. metricsClient may be nil, in which case panics are
+// recovered but not reported as metrics.
+func MakePanicListener(metricsClient MetricsClient) *PanicListener {
+	return &PanicListener{metricsClient: metricsClient}
+}
+
+func (pl *PanicListener) HandlerStarted(ctx context.Context, msg json.RawMessage) {}
+
+func (pl *PanicListener) HandlerFinished(ctx context.Context) {}
+
+func (pl *PanicListener) HandlerPanicked(ctx context.Context, recovered interface{}, stack []byte) {
+	// Written straight to stderr, rather than through the stdlib log
+	// package: LoggingListener uses that shared logger for structured JSON
+	// lines, and a multi-line stack trace interleaved into that stream
+	// would corrupt it.
+	fmt.Fprintf(os.Stderr, "datadog: recovered from panic in handler: %v\n%s\n", recovered, stack)
+
+	if pl.metricsClient == nil {
+		return
+	}
+	pl.metricsClient.AddDistributionMetric("aws.lambda.enhanced.errors", 1)
+}
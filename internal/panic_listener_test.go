@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockMetricsClient struct {
+	calls []struct {
+		name  string
+		value float64
+		tags  []string
+	}
+}
+
+func (m *mockMetricsClient) AddDistributionMetric(name string, value float64, tags ...string) {
+	m.calls = append(m.calls, struct {
+		name  string
+		value float64
+		tags  []string
+	}{name, value, tags})
+}
+
+func TestPanicListenerHandlerPanickedReportsMetric(t *testing.T) {
+	mc := &mockMetricsClient{}
+	pl := MakePanicListener(mc)
+
+	pl.HandlerPanicked(context.Background(), "boom", []byte("stack"))
+
+	assert.Len(t, mc.calls, 1)
+	assert.Equal(t, "aws.lambda.enhanced.errors", mc.calls[0].name)
+	assert.Equal(t, float64(1), mc.calls[0].value)
+}
+
+func TestPanicListenerNilMetricsClientIsNoOp(t *testing.T) {
+	pl := MakePanicListener(nil)
+
+	assert.NotPanics(t, func() {
+		pl.HandlerPanicked(context.Background(), "boom", []byte("stack"))
+	})
+}
+
+func TestPanicListenerStartedAndFinishedAreNoOps(t *testing.T) {
+	pl := MakePanicListener(nil)
+
+	assert.NotPanics(t, func() {
+		pl.HandlerStarted(context.Background(), nil)
+		pl.HandlerFinished(context.Background())
+	})
+}
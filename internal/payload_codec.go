@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/vmihailenco/msgpack"
+)
+
+// payloadCodecEnvVar, when set to the name of a registered codec, overrides
+// WrapHandler's first-byte sniffing for choosing how to decode the
+// invocation payload.
+const payloadCodecEnvVar = "DD_LAMBDA_PAYLOAD_CODEC"
+
+// PayloadCodec decodes and encodes the raw bytes of a Lambda invocation
+// payload. WrapHandler uses it to unmarshal the incoming event into the
+// type a handler expects.
+type PayloadCodec interface {
+	Unmarshal(data []byte, v interface{}) error
+	Marshal(v interface{}) ([]byte, error)
+	ContentType() string
+}
+
+// JSONCodec is the default PayloadCodec, matching how Lambda normally
+// invokes handlers.
+type JSONCodec struct{}
+
+// Unmarshal decodes JSON-encoded data into v.
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// Marshal encodes v as JSON.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// ContentType returns "application/json".
+func (JSONCodec) ContentType() string { return "application/json" }
+
+// MsgPackCodec decodes MessagePack-encoded payloads, used when a Lambda is
+// invoked directly with a msgpack body - for example via API Gateway binary
+// passthrough, or an SDK caller that would rather not pay JSON's encode
+// cost.
+type MsgPackCodec struct{}
+
+// Unmarshal decodes MessagePack-encoded data into v.
+func (MsgPackCodec) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+
+// Marshal encodes v as MessagePack.
+func (MsgPackCodec) Marshal(v interface{}) ([]byte, error) { return msgpack.Marshal(v) }
+
+// ContentType returns "application/msgpack".
+func (MsgPackCodec) ContentType() string { return "application/msgpack" }
+
+var namedPayloadCodecs = map[string]PayloadCodec{
+	"json":    JSONCodec{},
+	"msgpack": MsgPackCodec{},
+}
+
+// selectPayloadCodec picks the PayloadCodec WrapHandler should use to
+// decode msg. DD_LAMBDA_PAYLOAD_CODEC takes priority when it names a known
+// codec; otherwise the codec is inferred from msg's first significant
+// byte.
+func selectPayloadCodec(msg []byte) PayloadCodec {
+	if name := strings.ToLower(os.Getenv(payloadCodecEnvVar)); name != "" {
+		if codec, ok := namedPayloadCodecs[name]; ok {
+			return codec
+		}
+	}
+	if looksLikeJSON(msg) {
+		return JSONCodec{}
+	}
+	return MsgPackCodec{}
+}
+
+// looksLikeJSON reports whether msg's first non-whitespace byte is one that
+// can start a JSON value. It's used to distinguish a JSON payload from a
+// MessagePack one: MessagePack maps, arrays and strings begin with a byte at
+// or above 0x80, outside JSON's set of first characters. A MessagePack
+// payload that is a bare positive fixint (0x00-0x7f) can share its leading
+// byte with JSON and is then reported as JSON.
+func looksLikeJSON(msg []byte) bool {
+	for _, b := range msg {
+		switch {
+		case b == ' ' || b == '\t' || b == '\n' || b == '\r':
+			continue
+		case b == '{' || b == '[' || b == '"' || b == 't' || b == 'f' || b == 'n' || b == '-':
+			return true
+		case b >= '0' && b <= '9':
+			return true
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack"
+)
+
+func TestLooksLikeJSON(t *testing.T) {
+	assert.True(t, looksLikeJSON([]byte(`{"a":1}`)))
+	assert.True(t, looksLikeJSON([]byte(`  [1,2,3]`)))
+	assert.True(t, looksLikeJSON([]byte(`"hello"`)))
+	assert.True(t, looksLikeJSON([]byte(`null`)))
+	assert.True(t, looksLikeJSON([]byte(`-1.5`)))
+
+	packed, err := msgpack.Marshal(map[string]int{"a": 1})
+	assert.NoError(t, err)
+	assert.False(t, looksLikeJSON(packed))
+}
+
+func TestJSONCodecMarshalRoundTrips(t *testing.T) {
+	var codec JSONCodec
+
+	data, err := codec.Marshal(map[string]int{"a": 1})
+	assert.NoError(t, err)
+
+	var v map[string]int
+	assert.NoError(t, codec.Unmarshal(data, &v))
+	assert.Equal(t, map[string]int{"a": 1}, v)
+	assert.Equal(t, "application/json", codec.ContentType())
+}
+
+func TestMsgPackCodecMarshalRoundTrips(t *testing.T) {
+	var codec MsgPackCodec
+
+	data, err := codec.Marshal(map[string]int{"a": 1})
+	assert.NoError(t, err)
+
+	var v map[string]int
+	assert.NoError(t, codec.Unmarshal(data, &v))
+	assert.Equal(t, map[string]int{"a": 1}, v)
+	assert.Equal(t, "application/msgpack", codec.ContentType())
+}
+
+func TestSelectPayloadCodecEnvVarOverride(t *testing.T) {
+	os.Setenv(payloadCodecEnvVar, "msgpack")
+	defer os.Unsetenv(payloadCodecEnvVar)
+
+	codec := selectPayloadCodec([]byte(`{"a":1}`))
+
+	assert.IsType(t, MsgPackCodec{}, codec)
+}
+
+func TestSelectPayloadCodecSniffsByDefault(t *testing.T) {
+	assert.IsType(t, JSONCodec{}, selectPayloadCodec([]byte(`{"a":1}`)))
+
+	packed, err := msgpack.Marshal(map[string]int{"a": 1})
+	assert.NoError(t, err)
+	assert.IsType(t, MsgPackCodec{}, selectPayloadCodec(packed))
+}
+
+func TestWrapHandlerMsgPackEvent(t *testing.T) {
+	called := false
+
+	handler := func(ctx context.Context, request mockNonProxyEvent) (int, error) {
+		called = true
+		assert.Equal(t, "12345678910", request.FakeID)
+		return 5, nil
+	}
+
+	event := mockNonProxyEvent{
+		MyCustomEvent: map[string]int{"foo": 1},
+		FakeID:        "12345678910",
+	}
+	packed, err := msgpack.Marshal(event)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	mhl := mockHandlerListener{}
+	wrappedHandler := WrapHandler(handler, &mhl).(func(context.Context, json.RawMessage) (interface{}, error))
+
+	response, err := wrappedHandler(ctx, packed)
+
+	assert.True(t, called)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, response)
+}
@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime/debug"
+)
+
+var writerType = reflect.TypeOf((*io.Writer)(nil)).Elem()
+
+// streamingPrelude is written once, before any handler output, per the
+// Lambda Runtime API's streaming response convention: an HTTP-style status
+// line and headers declaring the
+// application/vnd.awslambda.http-integration-response content type,
+// followed by eight null bytes that mark the end of the prelude and the
+// start of the body.
+const streamingPrelude = "HTTP/1.1 200 OK\r\n" +
+	"Content-Type: application/vnd.awslambda.http-integration-response\r\n" +
+	"\r\n" +
+	"\x00\x00\x00\x00\x00\x00\x00\x00"
+
+// isStreamingHandler reports whether handler's last argument is an
+// io.Writer, the shape used for Lambda Response Streaming.
+func isStreamingHandler(handler reflect.Type) bool {
+	n := handler.NumIn()
+	return n > 0 && handler.In(n-1).Implements(writerType)
+}
+
+// validateStreamingHandler checks that handler has the shape
+// func(context.Context, io.Writer) error or
+// func(context.Context, E, io.Writer) error.
+func validateStreamingHandler(handler reflect.Type) error {
+	switch handler.NumIn() {
+	case 2, 3:
+	default:
+		return fmt.Errorf("streaming handler takes the wrong number of arguments")
+	}
+	if !handler.In(0).Implements(contextType) {
+		return fmt.Errorf("streaming handler should take context as first argument")
+	}
+	if handler.NumOut() != 1 || !handler.Out(0).Implements(errorType) {
+		return fmt.Errorf("streaming handler should return a single error")
+	}
+	return nil
+}
+
+// streamingResponseWriter writes the Lambda Runtime API streaming prelude
+// exactly once, on the first write, then forwards every subsequent Write
+// straight through to the underlying writer. If the handler never calls
+// Write at all - it returns an empty body, or panics before producing any
+// output - Close still flushes the prelude, so the invocation always ends
+// with at least a prelude written to w.
+type streamingResponseWriter struct {
+	w              io.Writer
+	preludeWritten bool
+}
+
+func (s *streamingResponseWriter) writePrelude() error {
+	if s.preludeWritten {
+		return nil
+	}
+	s.preludeWritten = true
+	_, err := io.WriteString(s.w, streamingPrelude)
+	return err
+}
+
+// Write implements io.Writer, writing the prelude first if this is the
+// first call.
+func (s *streamingResponseWriter) Write(p []byte) (int, error) {
+	if err := s.writePrelude(); err != nil {
+		return 0, err
+	}
+	return s.w.Write(p)
+}
+
+// Close is called once the wrapped handler returns. Listeners are only
+// notified of completion after Close returns, so a Close that flushes
+// buffered output (or a tracer span it owns) always runs before the
+// invocation is considered finished. It also writes the prelude if Write
+// was never called.
+func (s *streamingResponseWriter) Close() error {
+	return s.writePrelude()
+}
+
+// wrapStreamingHandler is WrapHandler's branch for the Lambda Response
+// Streaming handler shape: func(ctx, [event,] io.Writer) error. Unlike the
+// buffered shape, the returned function writes directly to w as the
+// handler produces output, instead of returning a value to be marshaled.
+func wrapStreamingHandler(handler interface{}, handlerType reflect.Type, listeners []HandlerListener) func(context.Context, json.RawMessage, io.Writer) error {
+	if err := validateStreamingHandler(handlerType); err != nil {
+		return func(ctx context.Context, msg json.RawMessage, w io.Writer) error {
+			return err
+		}
+	}
+
+	handlerValue := reflect.ValueOf(handler)
+	takesEvent := handlerType.NumIn() == 3
+
+	var eventType reflect.Type
+	if takesEvent {
+		eventType = handlerType.In(1)
+	}
+
+	return func(ctx context.Context, msg json.RawMessage, w io.Writer) (err error) {
+		if trace, ok := ExtractTraceContext(msg); ok {
+			ctx = ContextWithTraceContext(ctx, trace)
+		}
+
+		for _, listener := range listeners {
+			listener.HandlerStarted(ctx, msg)
+		}
+
+		streamWriter := &streamingResponseWriter{w: w}
+
+		defer func() {
+			closeErr := streamWriter.Close()
+
+			if recovered := recover(); recovered != nil {
+				stack := debug.Stack()
+				for i := len(listeners) - 1; i >= 0; i-- {
+					listeners[i].HandlerPanicked(ctx, recovered, stack)
+				}
+				err = fmt.Errorf("handler panicked: %v", recovered)
+				return
+			}
+			if err == nil {
+				err = closeErr
+			}
+			for i := len(listeners) - 1; i >= 0; i-- {
+				listeners[i].HandlerFinished(ctx)
+			}
+		}()
+
+		arguments := make([]reflect.Value, 0, 3)
+		arguments = append(arguments, reflect.ValueOf(ctx))
+		if takesEvent {
+			event := reflect.New(eventType)
+			if len(msg) > 0 {
+				codec := selectPayloadCodec(msg)
+				if unmarshalErr := codec.Unmarshal(msg, event.Interface()); unmarshalErr != nil {
+					return unmarshalErr
+				}
+			}
+			arguments = append(arguments, event.Elem())
+		}
+		arguments = append(arguments, reflect.ValueOf(streamWriter))
+
+		results := handlerValue.Call(arguments)
+		if !results[0].IsNil() {
+			return results[0].Interface().(error)
+		}
+		return nil
+	}
+}
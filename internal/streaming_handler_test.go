@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateStreamingHandlerWrongArgumentCount(t *testing.T) {
+	tooMany := func(ctx context.Context, a, b int, w io.Writer) error { return nil }
+
+	_, err := validateHandler(tooMany)
+	assert.EqualError(t, err, "streaming handler takes the wrong number of arguments")
+}
+
+func TestValidateStreamingHandlerMissingContext(t *testing.T) {
+	missingContext := func(a int, w io.Writer) error { return nil }
+
+	_, err := validateHandler(missingContext)
+	assert.EqualError(t, err, "streaming handler should take context as first argument")
+}
+
+func TestValidateStreamingHandlerWrongReturn(t *testing.T) {
+	wrongReturn := func(ctx context.Context, w io.Writer) (int, error) { return 0, nil }
+
+	_, err := validateHandler(wrongReturn)
+	assert.EqualError(t, err, "streaming handler should return a single error")
+}
+
+func TestWrapHandlerStreamingWritesPreludeAndChunks(t *testing.T) {
+	handler := func(ctx context.Context, request mockNonProxyEvent, w io.Writer) error {
+		io.WriteString(w, "chunk-one,")
+		io.WriteString(w, "chunk-two")
+		return nil
+	}
+
+	ctx := context.Background()
+	payload := loadRawJSON(t, "testdata/non-proxy-no-metadata.json")
+	wrapped := WrapHandler(handler).(func(context.Context, json.RawMessage, io.Writer) error)
+
+	var out bytes.Buffer
+	err := wrapped(ctx, *payload, &out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, streamingPrelude+"chunk-one,chunk-two", out.String())
+}
+
+func TestWrapHandlerStreamingFlushesPreludeOnEmptyBody(t *testing.T) {
+	handler := func(ctx context.Context, w io.Writer) error {
+		return nil
+	}
+
+	ctx := context.Background()
+	payload := loadRawJSON(t, "testdata/non-proxy-no-metadata.json")
+	wrapped := WrapHandler(handler).(func(context.Context, json.RawMessage, io.Writer) error)
+
+	var out bytes.Buffer
+	err := wrapped(ctx, *payload, &out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, streamingPrelude, out.String())
+}
+
+func TestWrapHandlerStreamingNoEventArgument(t *testing.T) {
+	called := false
+	handler := func(ctx context.Context, w io.Writer) error {
+		called = true
+		io.WriteString(w, "ok")
+		return nil
+	}
+
+	ctx := context.Background()
+	payload := loadRawJSON(t, "testdata/non-proxy-no-metadata.json")
+	wrapped := WrapHandler(handler).(func(context.Context, json.RawMessage, io.Writer) error)
+
+	var out bytes.Buffer
+	err := wrapped(ctx, *payload, &out)
+
+	assert.True(t, called)
+	assert.NoError(t, err)
+	assert.Equal(t, streamingPrelude+"ok", out.String())
+}
+
+func TestWrapHandlerStreamingPropagatesErrorAfterPartialWrite(t *testing.T) {
+	defaultErr := errors.New("ran out of data")
+	handler := func(ctx context.Context, w io.Writer) error {
+		io.WriteString(w, "partial-chunk")
+		return defaultErr
+	}
+
+	ctx := context.Background()
+	payload := loadRawJSON(t, "testdata/non-proxy-no-metadata.json")
+	wrapped := WrapHandler(handler).(func(context.Context, json.RawMessage, io.Writer) error)
+
+	var out bytes.Buffer
+	err := wrapped(ctx, *payload, &out)
+
+	assert.Equal(t, defaultErr, err)
+	assert.Equal(t, streamingPrelude+"partial-chunk", out.String())
+}
+
+func TestWrapHandlerStreamingNotifiesListeners(t *testing.T) {
+	var events []string
+	listener := &mockHandlerListener{name: "listener", events: &events}
+
+	handler := func(ctx context.Context, w io.Writer) error {
+		return nil
+	}
+
+	ctx := context.Background()
+	payload := loadRawJSON(t, "testdata/non-proxy-no-metadata.json")
+	wrapped := WrapHandler(handler, listener).(func(context.Context, json.RawMessage, io.Writer) error)
+
+	var out bytes.Buffer
+	err := wrapped(ctx, *payload, &out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"listener:started", "listener:finished"}, events)
+}
+
+func TestWrapHandlerStreamingRecoversPanic(t *testing.T) {
+	var events []string
+	listener := &mockHandlerListener{name: "listener", events: &events}
+
+	handler := func(ctx context.Context, w io.Writer) error {
+		panic("stream blew up")
+	}
+
+	ctx := context.Background()
+	payload := loadRawJSON(t, "testdata/non-proxy-no-metadata.json")
+	wrapped := WrapHandler(handler, listener).(func(context.Context, json.RawMessage, io.Writer) error)
+
+	var out bytes.Buffer
+	err := wrapped(ctx, *payload, &out)
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"listener:started", "listener:panicked"}, events)
+	// The handler panicked before ever calling Write, but the prelude
+	// should still have been flushed by Close.
+	assert.Equal(t, streamingPrelude, out.String())
+}
@@ -0,0 +1,293 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	traceIDHeader          = "x-datadog-trace-id"
+	parentIDHeader         = "x-datadog-parent-id"
+	samplingPriorityHeader = "x-datadog-sampling-priority"
+)
+
+// TraceContext carries the Datadog distributed-tracing identifiers
+// extracted from an incoming Lambda event, so the span created for this
+// invocation can be linked to whatever upstream service produced the
+// event.
+type TraceContext struct {
+	TraceID          string
+	ParentID         string
+	SamplingPriority string
+}
+
+// traceContextJSON is the `_datadog` payload Datadog's tracers inject into
+// event sources that support free-form metadata (SNS/SQS message
+// attributes, Kinesis/DynamoDB record data, EventBridge detail).
+type traceContextJSON struct {
+	TraceID          string `json:"x-datadog-trace-id"`
+	ParentID         string `json:"x-datadog-parent-id"`
+	SamplingPriority string `json:"x-datadog-sampling-priority"`
+}
+
+func (t traceContextJSON) toTraceContext() *TraceContext {
+	if t.TraceID == "" {
+		return nil
+	}
+	return &TraceContext{
+		TraceID:          t.TraceID,
+		ParentID:         t.ParentID,
+		SamplingPriority: t.SamplingPriority,
+	}
+}
+
+// EventCarrier extracts a TraceContext out of a raw Lambda event payload.
+// Implementations should report ok == false when the payload isn't theirs
+// to handle (rather than returning an error), so ExtractTraceContext can
+// fall through to the next registered carrier.
+type EventCarrier interface {
+	Extract(msg json.RawMessage) (trace *TraceContext, ok bool)
+}
+
+// EventCarrierFunc adapts a plain function to the EventCarrier interface.
+type EventCarrierFunc func(msg json.RawMessage) (*TraceContext, bool)
+
+// Extract calls f.
+func (f EventCarrierFunc) Extract(msg json.RawMessage) (*TraceContext, bool) {
+	return f(msg)
+}
+
+var eventCarriers = []EventCarrier{
+	EventCarrierFunc(extractFromHTTPEvent),
+	EventCarrierFunc(extractFromSNSEvent),
+	EventCarrierFunc(extractFromSQSEvent),
+	EventCarrierFunc(extractFromKinesisEvent),
+	EventCarrierFunc(extractFromDynamoDBEvent),
+	EventCarrierFunc(extractFromEventBridgeEvent),
+	EventCarrierFunc(extractFromS3Event),
+}
+
+// RegisterEventCarrier adds carrier to the front of the list tried by
+// ExtractTraceContext, so a custom event shape can be supported without
+// modifying this package. Carriers registered this way are tried before
+// any of the built-in ones, so a custom carrier can also override how a
+// built-in event source is handled.
+func RegisterEventCarrier(carrier EventCarrier) {
+	eventCarriers = append([]EventCarrier{carrier}, eventCarriers...)
+}
+
+// ExtractTraceContext tries each registered EventCarrier in turn and
+// returns the TraceContext produced by the first one that recognizes msg.
+// It returns ok == false if no carrier recognized the event, or the event
+// didn't carry Datadog trace metadata.
+func ExtractTraceContext(msg json.RawMessage) (trace *TraceContext, ok bool) {
+	for _, carrier := range eventCarriers {
+		if trace, ok := carrier.Extract(msg); ok {
+			return trace, true
+		}
+	}
+	return nil, false
+}
+
+// extractFromHTTPEvent handles API Gateway (REST and HTTP API) proxy
+// requests and ALB target group requests, all of which carry trace headers
+// as a plain header map.
+func extractFromHTTPEvent(msg json.RawMessage) (*TraceContext, bool) {
+	var apiGW events.APIGatewayProxyRequest
+	if err := json.Unmarshal(msg, &apiGW); err == nil && apiGW.RequestContext.RequestID != "" {
+		return extractFromHeaders(apiGW.Headers)
+	}
+
+	var alb events.ALBTargetGroupRequest
+	if err := json.Unmarshal(msg, &alb); err == nil && alb.RequestContext.ELB.TargetGroupArn != "" {
+		return extractFromHeaders(alb.Headers)
+	}
+
+	return nil, false
+}
+
+func extractFromHeaders(headers map[string]string) (*TraceContext, bool) {
+	lower := make(map[string]string, len(headers))
+	for k, v := range headers {
+		lower[strings.ToLower(k)] = v
+	}
+
+	traceID, ok := lower[traceIDHeader]
+	if !ok {
+		return nil, false
+	}
+	return &TraceContext{
+		TraceID:          traceID,
+		ParentID:         lower[parentIDHeader],
+		SamplingPriority: lower[samplingPriorityHeader],
+	}, true
+}
+
+// extractFromSNSEvent handles direct SNS-to-Lambda subscriptions, reading
+// the Datadog trace context out of the notification's MessageAttributes.
+func extractFromSNSEvent(msg json.RawMessage) (*TraceContext, bool) {
+	var snsEvent events.SNSEvent
+	if err := json.Unmarshal(msg, &snsEvent); err != nil || len(snsEvent.Records) == 0 {
+		return nil, false
+	}
+	record := snsEvent.Records[0]
+	if record.EventSource != "aws:sns" {
+		return nil, false
+	}
+	return extractFromSNSMessageAttributes(record.SNS.MessageAttributes)
+}
+
+func extractFromSNSMessageAttributes(attrs map[string]interface{}) (*TraceContext, bool) {
+	ddAttr, ok := attrs["_datadog"]
+	if !ok {
+		return nil, false
+	}
+	ddAttrMap, ok := ddAttr.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	value, ok := ddAttrMap["Value"].(string)
+	if !ok {
+		return nil, false
+	}
+
+	var trace traceContextJSON
+	if err := json.Unmarshal([]byte(value), &trace); err != nil {
+		return nil, false
+	}
+	context := trace.toTraceContext()
+	return context, context != nil
+}
+
+// extractFromSQSEvent handles SQS events, including the common SNS-to-SQS
+// fan-out pattern where the original SNS notification (and its message
+// attributes) is embedded as the JSON body of the SQS message.
+func extractFromSQSEvent(msg json.RawMessage) (*TraceContext, bool) {
+	var sqsEvent events.SQSEvent
+	if err := json.Unmarshal(msg, &sqsEvent); err != nil || len(sqsEvent.Records) == 0 {
+		return nil, false
+	}
+	record := sqsEvent.Records[0]
+	if record.EventSource != "aws:sqs" {
+		return nil, false
+	}
+
+	if attr, ok := record.MessageAttributes["_datadog"]; ok && attr.StringValue != nil {
+		var trace traceContextJSON
+		if err := json.Unmarshal([]byte(*attr.StringValue), &trace); err == nil {
+			if context := trace.toTraceContext(); context != nil {
+				return context, true
+			}
+		}
+	}
+
+	var snsEnvelope struct {
+		MessageAttributes map[string]interface{} `json:"MessageAttributes"`
+	}
+	if err := json.Unmarshal([]byte(record.Body), &snsEnvelope); err == nil {
+		return extractFromSNSMessageAttributes(snsEnvelope.MessageAttributes)
+	}
+
+	return nil, false
+}
+
+// extractFromKinesisEvent handles Kinesis Data Streams records. The data
+// payload is base64 in the wire format, but encoding/json already decodes
+// it for us because events.KinesisRecord.Kinesis.Data is typed []byte.
+func extractFromKinesisEvent(msg json.RawMessage) (*TraceContext, bool) {
+	var kinesisEvent events.KinesisEvent
+	if err := json.Unmarshal(msg, &kinesisEvent); err != nil || len(kinesisEvent.Records) == 0 {
+		return nil, false
+	}
+	record := kinesisEvent.Records[0]
+	if record.EventSource != "aws:kinesis" {
+		return nil, false
+	}
+	return extractFromEncodedDatadogField(record.Kinesis.Data)
+}
+
+func extractFromEncodedDatadogField(data []byte) (*TraceContext, bool) {
+	var payload struct {
+		Datadog traceContextJSON `json:"_datadog"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, false
+	}
+	context := payload.Datadog.toTraceContext()
+	return context, context != nil
+}
+
+// extractFromDynamoDBEvent handles DynamoDB Streams records, reading the
+// trace context out of a "_datadog" string attribute on the new image.
+func extractFromDynamoDBEvent(msg json.RawMessage) (*TraceContext, bool) {
+	var ddbEvent events.DynamoDBEvent
+	if err := json.Unmarshal(msg, &ddbEvent); err != nil || len(ddbEvent.Records) == 0 {
+		return nil, false
+	}
+	record := ddbEvent.Records[0]
+	if record.EventSource != "aws:dynamodb" {
+		return nil, false
+	}
+
+	attr, ok := record.Change.NewImage["_datadog"]
+	if !ok {
+		return nil, false
+	}
+
+	var trace traceContextJSON
+	if err := json.Unmarshal([]byte(attr.String()), &trace); err != nil {
+		return nil, false
+	}
+	context := trace.toTraceContext()
+	return context, context != nil
+}
+
+// extractFromEventBridgeEvent handles EventBridge (CloudWatch Events)
+// events, reading the trace context out of a "_datadog" field on the
+// top-level detail object.
+func extractFromEventBridgeEvent(msg json.RawMessage) (*TraceContext, bool) {
+	var ebEvent events.CloudWatchEvent
+	if err := json.Unmarshal(msg, &ebEvent); err != nil || ebEvent.DetailType == "" {
+		return nil, false
+	}
+
+	var detail struct {
+		Datadog traceContextJSON `json:"_datadog"`
+	}
+	if err := json.Unmarshal(ebEvent.Detail, &detail); err != nil {
+		return nil, false
+	}
+	context := detail.Datadog.toTraceContext()
+	return context, context != nil
+}
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a copy of ctx carrying trace, so it can be
+// retrieved later with TraceContextFromContext.
+func ContextWithTraceContext(ctx context.Context, trace *TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, trace)
+}
+
+// TraceContextFromContext returns the TraceContext WrapHandler extracted
+// from the incoming event, if the event carried one.
+func TraceContextFromContext(ctx context.Context) (*TraceContext, bool) {
+	trace, ok := ctx.Value(traceContextKey{}).(*TraceContext)
+	return trace, ok && trace != nil
+}
+
+// extractFromS3Event recognizes S3 notification events but never extracts
+// a trace context from them: S3 notifications have no field a producer
+// could use to stamp Datadog metadata onto, so there's nothing to read.
+// It always reports ok == false; a custom EventCarrier added with
+// RegisterEventCarrier is tried before it and can handle S3 events instead.
+func extractFromS3Event(msg json.RawMessage) (*TraceContext, bool) {
+	var s3Event events.S3Event
+	if err := json.Unmarshal(msg, &s3Event); err != nil || len(s3Event.Records) == 0 {
+		return nil, false
+	}
+	return nil, false
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractTraceContext(t *testing.T) {
+	cases := []struct {
+		name     string
+		file     string
+		expected *TraceContext
+	}{
+		{
+			name: "api gateway proxy request",
+			file: "testdata/apig-event-no-metadata.json",
+			// fixture has no trace headers, so nothing should be extracted
+			expected: nil,
+		},
+		{
+			name: "alb target group request",
+			file: "testdata/alb-event.json",
+			expected: &TraceContext{
+				TraceID:          "7890123456",
+				ParentID:         "4567890123",
+				SamplingPriority: "1",
+			},
+		},
+		{
+			name: "sns notification",
+			file: "testdata/sns-event.json",
+			expected: &TraceContext{
+				TraceID:          "111111",
+				ParentID:         "222222",
+				SamplingPriority: "1",
+			},
+		},
+		{
+			name: "sqs message",
+			file: "testdata/sqs-event.json",
+			expected: &TraceContext{
+				TraceID:          "333333",
+				ParentID:         "444444",
+				SamplingPriority: "1",
+			},
+		},
+		{
+			name: "sns fan-out to sqs",
+			file: "testdata/sqs-sns-event.json",
+			expected: &TraceContext{
+				TraceID:          "555555",
+				ParentID:         "666666",
+				SamplingPriority: "1",
+			},
+		},
+		{
+			name: "kinesis record",
+			file: "testdata/kinesis-event.json",
+			expected: &TraceContext{
+				TraceID:          "777777",
+				ParentID:         "888888",
+				SamplingPriority: "1",
+			},
+		},
+		{
+			name: "dynamodb stream record",
+			file: "testdata/dynamodb-event.json",
+			expected: &TraceContext{
+				TraceID:          "999999",
+				ParentID:         "101010",
+				SamplingPriority: "1",
+			},
+		},
+		{
+			name: "eventbridge event",
+			file: "testdata/eventbridge-event.json",
+			expected: &TraceContext{
+				TraceID:          "123123",
+				ParentID:         "456456",
+				SamplingPriority: "1",
+			},
+		},
+		{
+			name:     "s3 event",
+			file:     "testdata/s3-event.json",
+			expected: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg := loadRawJSON(t, tc.file)
+
+			trace, ok := ExtractTraceContext(*msg)
+
+			if tc.expected == nil {
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, tc.expected, trace)
+		})
+	}
+}
+
+// TestExtractFromSNSMessageAttributes exercises the helper directly against
+// the shape encoding/json actually produces for SNSEntity.MessageAttributes
+// (map[string]interface{}, since SNS attribute values are untyped), rather
+// than only indirectly through the fixture-driven table above, so a future
+// change can't reintroduce a struct type SNS message attributes don't have.
+func TestExtractFromSNSMessageAttributes(t *testing.T) {
+	attrs := map[string]interface{}{
+		"_datadog": map[string]interface{}{
+			"Type":  "String",
+			"Value": `{"x-datadog-trace-id":"111111","x-datadog-parent-id":"222222","x-datadog-sampling-priority":"1"}`,
+		},
+	}
+
+	trace, ok := extractFromSNSMessageAttributes(attrs)
+
+	assert.True(t, ok)
+	assert.Equal(t, &TraceContext{TraceID: "111111", ParentID: "222222", SamplingPriority: "1"}, trace)
+}
+
+func TestExtractFromSNSMessageAttributesMissingValue(t *testing.T) {
+	attrs := map[string]interface{}{
+		"_datadog": map[string]interface{}{
+			"Type": "String",
+		},
+	}
+
+	trace, ok := extractFromSNSMessageAttributes(attrs)
+
+	assert.False(t, ok)
+	assert.Nil(t, trace)
+}
+
+type mockEventCarrier struct {
+	trace *TraceContext
+}
+
+func (m mockEventCarrier) Extract(msg json.RawMessage) (*TraceContext, bool) {
+	return m.trace, m.trace != nil
+}
+
+func TestRegisterEventCarrierTakesPrecedence(t *testing.T) {
+	original := eventCarriers
+	defer func() { eventCarriers = original }()
+
+	custom := &TraceContext{TraceID: "custom-trace"}
+	RegisterEventCarrier(mockEventCarrier{trace: custom})
+
+	msg := loadRawJSON(t, "testdata/alb-event.json")
+	trace, ok := ExtractTraceContext(*msg)
+
+	assert.True(t, ok)
+	assert.Equal(t, custom, trace)
+}
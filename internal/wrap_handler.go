@@ -0,0 +1,174 @@
+// Package internal contains the plumbing behind the public datadog-lambda-go
+// API: wrapping a customer's Lambda handler so invocations can be observed
+// and instrumented without the customer needing to change their handler
+// signature.
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+)
+
+var (
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// validateArguments inspects a handler's parameters and reports whether the
+// handler takes a context.Context as its first argument.
+func validateArguments(handler reflect.Type) (takesContext bool, err error) {
+	if handler.NumIn() > 2 {
+		return false, fmt.Errorf("handler takes too many arguments")
+	}
+	if handler.NumIn() == 0 {
+		return false, nil
+	}
+
+	takesContext = handler.In(0).Implements(contextType)
+	if handler.NumIn() > 1 && !takesContext {
+		return false, fmt.Errorf("handler should take context as first argument")
+	}
+	return takesContext, nil
+}
+
+// validateReturns inspects a handler's return values. A handler may return
+// nothing, a single value, or a value and an error, but if it returns two
+// values the second must be an error.
+func validateReturns(handler reflect.Type) error {
+	switch n := handler.NumOut(); {
+	case n > 2:
+		return fmt.Errorf("handler returns more than two values")
+	case n == 2:
+		if !handler.Out(1).Implements(errorType) {
+			return fmt.Errorf("handler doesn't return error as it's last value")
+		}
+	}
+	return nil
+}
+
+// validateHandler checks that handler is a function with a shape the
+// wrapper knows how to call: an optional context.Context first argument, an
+// optional single event argument, and up to two return values with the
+// last being an error. A handler whose last argument is an io.Writer is
+// checked as a Lambda Response Streaming handler by validateStreamingHandler
+// instead, and takesContext is then reported as false. WrapHandler builds
+// its buffered closures from this result; for streaming handlers it calls
+// wrapStreamingHandler, which runs validateStreamingHandler itself.
+func validateHandler(handler interface{}) (takesContext bool, err error) {
+	handlerType := reflect.TypeOf(handler)
+	if handlerType == nil || handlerType.Kind() != reflect.Func {
+		return false, fmt.Errorf("handler is not a function")
+	}
+
+	if isStreamingHandler(handlerType) {
+		return false, validateStreamingHandler(handlerType)
+	}
+
+	takesContext, err = validateArguments(handlerType)
+	if err != nil {
+		return false, err
+	}
+	return takesContext, validateReturns(handlerType)
+}
+
+// WrapHandler wraps handler so that every invocation notifies listeners (in
+// registration order on start, reverse order on finish), so that a panic
+// inside handler is recovered and turned into a returned error instead of
+// crashing the Lambda process, so that any Datadog trace context found in
+// the incoming event is attached to the context passed to handler and
+// listeners (retrievable with TraceContextFromContext), and so that the
+// event payload is decoded with whichever PayloadCodec matches it (see
+// selectPayloadCodec), allowing JSON and MessagePack invocations alike. The
+// returned value has the shape func(context.Context, json.RawMessage)
+// (interface{}, error), which is what the Lambda Go runtime's
+// reflection-based invoker expects - except when handler is a Lambda
+// Response Streaming handler (its last argument is an io.Writer), in which
+// case WrapHandler returns a func(context.Context, json.RawMessage,
+// io.Writer) error instead; see wrapStreamingHandler.
+func WrapHandler(handler interface{}, listeners ...HandlerListener) interface{} {
+	// Streaming handlers are routed to their own wrapping/validation before
+	// validateHandler runs, since they return a differently-shaped wrapped
+	// function (it writes to an io.Writer instead of returning a value).
+	if handlerType := reflect.TypeOf(handler); handlerType != nil && handlerType.Kind() == reflect.Func && isStreamingHandler(handlerType) {
+		return wrapStreamingHandler(handler, handlerType, listeners)
+	}
+
+	takesContext, err := validateHandler(handler)
+	if err != nil {
+		return func(ctx context.Context, msg json.RawMessage) (interface{}, error) {
+			return nil, err
+		}
+	}
+
+	handlerType := reflect.TypeOf(handler)
+	handlerValue := reflect.ValueOf(handler)
+
+	var eventType reflect.Type
+	switch {
+	case takesContext && handlerType.NumIn() > 1:
+		eventType = handlerType.In(1)
+	case !takesContext && handlerType.NumIn() > 0:
+		eventType = handlerType.In(0)
+	}
+
+	return func(ctx context.Context, msg json.RawMessage) (response interface{}, err error) {
+		if trace, ok := ExtractTraceContext(msg); ok {
+			ctx = ContextWithTraceContext(ctx, trace)
+		}
+
+		for _, listener := range listeners {
+			listener.HandlerStarted(ctx, msg)
+		}
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				stack := debug.Stack()
+				for i := len(listeners) - 1; i >= 0; i-- {
+					listeners[i].HandlerPanicked(ctx, recovered, stack)
+				}
+				response, err = nil, fmt.Errorf("handler panicked: %v", recovered)
+				return
+			}
+			for i := len(listeners) - 1; i >= 0; i-- {
+				listeners[i].HandlerFinished(ctx)
+			}
+		}()
+
+		arguments := make([]reflect.Value, 0, 2)
+		if takesContext {
+			arguments = append(arguments, reflect.ValueOf(ctx))
+		}
+		if eventType != nil {
+			event := reflect.New(eventType)
+			if len(msg) > 0 {
+				codec := selectPayloadCodec(msg)
+				if unmarshalErr := codec.Unmarshal(msg, event.Interface()); unmarshalErr != nil {
+					return nil, unmarshalErr
+				}
+			}
+			arguments = append(arguments, event.Elem())
+		}
+
+		return unpackResults(handlerValue.Call(arguments))
+	}
+}
+
+func unpackResults(results []reflect.Value) (response interface{}, err error) {
+	switch len(results) {
+	case 1:
+		if errVal, ok := results[0].Interface().(error); ok {
+			err = errVal
+		} else {
+			response = results[0].Interface()
+		}
+	case 2:
+		response = results[0].Interface()
+		if !results[1].IsNil() {
+			err = results[1].Interface().(error)
+		}
+	}
+	return response, err
+}
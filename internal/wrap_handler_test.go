@@ -13,9 +13,13 @@ import (
 
 type (
 	mockHandlerListener struct {
-		inputCTX  context.Context
-		inputMSG  json.RawMessage
-		outputCTX context.Context
+		name           string
+		events         *[]string
+		inputCTX       context.Context
+		inputMSG       json.RawMessage
+		outputCTX      context.Context
+		recovered      interface{}
+		recoveredStack []byte
 	}
 
 	mockNonProxyEvent struct {
@@ -27,10 +31,25 @@ type (
 func (mhl *mockHandlerListener) HandlerStarted(ctx context.Context, msg json.RawMessage) {
 	mhl.inputCTX = ctx
 	mhl.inputMSG = msg
+	if mhl.events != nil {
+		*mhl.events = append(*mhl.events, mhl.name+":started")
+	}
 }
 
 func (mhl *mockHandlerListener) HandlerFinished(ctx context.Context) {
 	mhl.outputCTX = ctx
+	if mhl.events != nil {
+		*mhl.events = append(*mhl.events, mhl.name+":finished")
+	}
+}
+
+func (mhl *mockHandlerListener) HandlerPanicked(ctx context.Context, recovered interface{}, stack []byte) {
+	mhl.outputCTX = ctx
+	mhl.recovered = recovered
+	mhl.recoveredStack = stack
+	if mhl.events != nil {
+		*mhl.events = append(*mhl.events, mhl.name+":panicked")
+	}
 }
 
 func runHandlerWithJSON(t *testing.T, filename string, handler interface{}) (*mockHandlerListener, interface{}, error) {
@@ -59,14 +78,14 @@ func loadRawJSON(t *testing.T, filename string) *json.RawMessage {
 func TestValidateHandlerNotFunction(t *testing.T) {
 	nonFunction := 1
 
-	err := validateHandler(nonFunction)
+	_, err := validateHandler(nonFunction)
 	assert.EqualError(t, err, "handler is not a function")
 }
 func TestValidateHandlerToManyArguments(t *testing.T) {
 	tooManyArgs := func(a, b, c int) {
 	}
 
-	err := validateHandler(tooManyArgs)
+	_, err := validateHandler(tooManyArgs)
 	assert.EqualError(t, err, "handler takes too many arguments")
 }
 
@@ -74,7 +93,7 @@ func TestValidateHandlerContextIsNotFirstArgument(t *testing.T) {
 	firstArgNotContext := func(arg1, arg2 int) {
 	}
 
-	err := validateHandler(firstArgNotContext)
+	_, err := validateHandler(firstArgNotContext)
 	assert.EqualError(t, err, "handler should take context as first argument")
 }
 
@@ -82,16 +101,18 @@ func TestValidateHandlerTwoArguments(t *testing.T) {
 	twoArguments := func(arg1 context.Context, arg2 int) {
 	}
 
-	err := validateHandler(twoArguments)
+	takesContext, err := validateHandler(twoArguments)
 	assert.NoError(t, err)
+	assert.True(t, takesContext)
 }
 
 func TestValidateHandlerOneArgument(t *testing.T) {
 	oneArgument := func(arg1 int) {
 	}
 
-	err := validateHandler(oneArgument)
+	takesContext, err := validateHandler(oneArgument)
 	assert.NoError(t, err)
+	assert.False(t, takesContext)
 }
 
 func TestValidateHandlerTooManyReturnValues(t *testing.T) {
@@ -99,7 +120,7 @@ func TestValidateHandlerTooManyReturnValues(t *testing.T) {
 		return 0, 0, nil
 	}
 
-	err := validateHandler(tooManyReturns)
+	_, err := validateHandler(tooManyReturns)
 	assert.EqualError(t, err, "handler returns more than two values")
 }
 func TestValidateHandlerLastReturnValueNotError(t *testing.T) {
@@ -107,7 +128,7 @@ func TestValidateHandlerLastReturnValueNotError(t *testing.T) {
 		return 0, 0
 	}
 
-	err := validateHandler(lastNotError)
+	_, err := validateHandler(lastNotError)
 	assert.EqualError(t, err, "handler doesn't return error as it's last value")
 }
 func TestValidateHandlerCorrectFormat(t *testing.T) {
@@ -115,8 +136,27 @@ func TestValidateHandlerCorrectFormat(t *testing.T) {
 		return 0, nil
 	}
 
-	err := validateHandler(correct)
+	takesContext, err := validateHandler(correct)
 	assert.NoError(t, err)
+	assert.True(t, takesContext)
+}
+
+func TestWrapHandlerNotFunctionReturnsError(t *testing.T) {
+	wrapped := WrapHandler(42).(func(context.Context, json.RawMessage) (interface{}, error))
+
+	response, err := wrapped(context.Background(), json.RawMessage(`{}`))
+
+	assert.EqualError(t, err, "handler is not a function")
+	assert.Nil(t, response)
+}
+
+func TestWrapHandlerNilReturnsError(t *testing.T) {
+	wrapped := WrapHandler(nil).(func(context.Context, json.RawMessage) (interface{}, error))
+
+	response, err := wrapped(context.Background(), json.RawMessage(`{}`))
+
+	assert.EqualError(t, err, "handler is not a function")
+	assert.Nil(t, response)
 }
 
 func TestWrapHandlerAPIGEvent(t *testing.T) {
@@ -212,3 +252,81 @@ func TestWrapHandlerReturnsError(t *testing.T) {
 	assert.Equal(t, defaultErr, err)
 	assert.Equal(t, 5, response)
 }
+
+func TestWrapHandlerExtractsTraceContext(t *testing.T) {
+	var extracted *TraceContext
+
+	handler := func(ctx context.Context, request mockNonProxyEvent) (int, error) {
+		extracted, _ = TraceContextFromContext(ctx)
+		return 5, nil
+	}
+
+	_, response, err := runHandlerWithJSON(t, "testdata/alb-event.json", handler)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, response)
+	assert.Equal(t, &TraceContext{
+		TraceID:          "7890123456",
+		ParentID:         "4567890123",
+		SamplingPriority: "1",
+	}, extracted)
+}
+
+func TestWrapHandlerListenerOrdering(t *testing.T) {
+	var events []string
+	first := &mockHandlerListener{name: "first", events: &events}
+	second := &mockHandlerListener{name: "second", events: &events}
+
+	handler := func(request mockNonProxyEvent) (int, error) {
+		return 5, nil
+	}
+
+	ctx := context.Background()
+	payload := loadRawJSON(t, "testdata/non-proxy-no-metadata.json")
+	wrappedHandler := WrapHandler(handler, first, second).(func(context.Context, json.RawMessage) (interface{}, error))
+
+	_, err := wrappedHandler(ctx, *payload)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first:started", "second:started", "second:finished", "first:finished"}, events)
+}
+
+func TestWrapHandlerRecoversPanic(t *testing.T) {
+	var events []string
+	listener := &mockHandlerListener{name: "listener", events: &events}
+
+	handler := func(request mockNonProxyEvent) (int, error) {
+		panic("something went wrong")
+	}
+
+	ctx := context.Background()
+	payload := loadRawJSON(t, "testdata/non-proxy-no-metadata.json")
+	wrappedHandler := WrapHandler(handler, listener).(func(context.Context, json.RawMessage) (interface{}, error))
+
+	response, err := wrappedHandler(ctx, *payload)
+
+	assert.Nil(t, response)
+	assert.Error(t, err)
+	assert.Equal(t, "something went wrong", listener.recovered)
+	assert.NotEmpty(t, listener.recoveredStack)
+	assert.Equal(t, []string{"listener:started", "listener:panicked"}, events)
+}
+
+func TestWrapHandlerPanicNotifiesAllListenersInReverseOrder(t *testing.T) {
+	var events []string
+	first := &mockHandlerListener{name: "first", events: &events}
+	second := &mockHandlerListener{name: "second", events: &events}
+
+	handler := func(request mockNonProxyEvent) (int, error) {
+		panic("boom")
+	}
+
+	ctx := context.Background()
+	payload := loadRawJSON(t, "testdata/non-proxy-no-metadata.json")
+	wrappedHandler := WrapHandler(handler, first, second).(func(context.Context, json.RawMessage) (interface{}, error))
+
+	_, err := wrappedHandler(ctx, *payload)
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"first:started", "second:started", "second:panicked", "first:panicked"}, events)
+}